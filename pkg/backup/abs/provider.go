@@ -0,0 +1,100 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package abs implements backup.BackupProvider against an Azure Blob
+// Storage container.
+package abs
+
+import (
+	"io"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/backup"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"golang.org/x/net/context"
+)
+
+// Context holds the Azure Blob Storage configuration for a cluster's backups.
+type Context struct {
+	AccountName string
+	AccountKey  string
+	Container   string
+	Prefix      string
+}
+
+// Provider implements backup.BackupProvider against an Azure Blob container.
+type Provider struct {
+	Context
+
+	container *storage.Container
+}
+
+func New(ctx Context) *Provider {
+	return &Provider{Context: ctx}
+}
+
+func (p *Provider) Setup(ctx context.Context) error {
+	cli, err := storage.NewBasicClient(p.AccountName, p.AccountKey)
+	if err != nil {
+		return err
+	}
+	bs := cli.GetBlobService()
+	p.container = bs.GetContainerReference(p.Container)
+	_, err = p.container.CreateIfNotExists(nil)
+	return err
+}
+
+func (p *Provider) Upload(name string, r io.Reader) error {
+	blob := p.container.GetBlobReference(p.keyFor(name))
+	return blob.CreateBlockBlobFromReader(r, nil)
+}
+
+func (p *Provider) Download(name string) (io.ReadCloser, error) {
+	blob := p.container.GetBlobReference(p.keyFor(name))
+	return blob.Get(nil)
+}
+
+func (p *Provider) List() ([]backup.BackupInfo, error) {
+	resp, err := p.container.ListBlobs(storage.ListBlobsParameters{Prefix: p.Prefix})
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]backup.BackupInfo, 0, len(resp.Blobs))
+	for _, b := range resp.Blobs {
+		infos = append(infos, backup.BackupInfo{
+			Name:      strings.TrimPrefix(b.Name, p.Prefix+"/"),
+			Size:      b.Properties.ContentLength,
+			CreatedAt: b.Properties.LastModified,
+		})
+	}
+	return infos, nil
+}
+
+func (p *Provider) Delete(name string) error {
+	blob := p.container.GetBlobReference(p.keyFor(name))
+	_, err := blob.DeleteIfExists(nil)
+	return err
+}
+
+// Cleanup is a no-op: we never own the container's lifecycle, only the
+// blobs we wrote into it.
+func (p *Provider) Cleanup() error {
+	return nil
+}
+
+func (p *Provider) keyFor(name string) string {
+	return p.Prefix + "/" + name
+}
@@ -0,0 +1,115 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gcs implements backup.BackupProvider against a Google Cloud
+// Storage bucket.
+package gcs
+
+import (
+	"io"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/backup"
+
+	"cloud.google.com/go/storage"
+	"golang.org/x/net/context"
+	"google.golang.org/api/iterator"
+)
+
+// Context holds the GCS-specific configuration for a cluster's backups.
+type Context struct {
+	// ProjectID is only used to create Bucket if it doesn't already exist.
+	ProjectID string
+	Bucket    string
+	Prefix    string
+}
+
+// Provider implements backup.BackupProvider against a GCS bucket.
+type Provider struct {
+	Context
+
+	cli *storage.Client
+	bkt *storage.BucketHandle
+}
+
+func New(ctx Context) *Provider {
+	return &Provider{Context: ctx}
+}
+
+func (p *Provider) Setup(ctx context.Context) error {
+	cli, err := storage.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	p.cli = cli
+	p.bkt = cli.Bucket(p.Bucket)
+
+	if _, err := p.bkt.Attrs(ctx); err != storage.ErrBucketNotExist {
+		return err
+	}
+	return p.bkt.Create(ctx, p.ProjectID, nil)
+}
+
+func (p *Provider) Upload(name string, r io.Reader) error {
+	ctx := context.Background()
+	w := p.bkt.Object(p.keyFor(name)).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (p *Provider) Download(name string) (io.ReadCloser, error) {
+	return p.bkt.Object(p.keyFor(name)).NewReader(context.Background())
+}
+
+func (p *Provider) List() ([]backup.BackupInfo, error) {
+	ctx := context.Background()
+	it := p.bkt.Objects(ctx, &storage.Query{Prefix: p.Prefix})
+	var infos []backup.BackupInfo
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, backup.BackupInfo{
+			Name:      strings.TrimPrefix(attrs.Name, p.Prefix+"/"),
+			Size:      attrs.Size,
+			CreatedAt: attrs.Created,
+		})
+	}
+	return infos, nil
+}
+
+func (p *Provider) Delete(name string) error {
+	err := p.bkt.Object(p.keyFor(name)).Delete(context.Background())
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+	return err
+}
+
+// Cleanup is a no-op: we never own the bucket's lifecycle, only the objects
+// we wrote into it.
+func (p *Provider) Cleanup() error {
+	return nil
+}
+
+func (p *Provider) keyFor(name string) string {
+	return p.Prefix + "/" + name
+}
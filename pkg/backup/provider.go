@@ -0,0 +1,71 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backup defines the storage-agnostic side of the backup manager:
+// a common BackupProvider interface that every concrete destination (S3,
+// GCS, Azure Blob, a PersistentVolume) implements, so the cluster package
+// can work with backups without knowing which one is in play.
+package backup
+
+import (
+	"io"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// StorageType identifies which BackupProvider backs a cluster's backups.
+// It is the discriminator stored in spec.BackupPolicy.StorageType.
+type StorageType string
+
+const (
+	StorageTypeS3               StorageType = "S3"
+	StorageTypeGCS              StorageType = "GCS"
+	StorageTypeABS              StorageType = "ABS"
+	StorageTypePersistentVolume StorageType = "PersistentVolume"
+)
+
+// BackupInfo describes one backup as listed from a provider, independent of
+// how that provider actually stores the bytes.
+type BackupInfo struct {
+	Name      string
+	Size      int64
+	Version   string
+	CreatedAt time.Time
+}
+
+// BackupProvider is implemented by each concrete backup destination. The
+// backup manager drives one of these rather than talking to S3, GCS, Azure
+// Blob, or a PV directly, following the same dispatch-behind-an-interface
+// pattern the tidb-operator backup-manager uses for its backup tools.
+type BackupProvider interface {
+	// Setup prepares the destination (creating a bucket/container/directory
+	// if needed) and must be safe to call every time the manager starts up.
+	Setup(ctx context.Context) error
+	// Upload writes a backup under name, overwriting any existing object
+	// of that name.
+	Upload(name string, r io.Reader) error
+	// Download opens a backup for reading. The caller must close it.
+	Download(name string) (io.ReadCloser, error)
+	// List returns all backups known to the provider, in no particular order.
+	List() ([]BackupInfo, error)
+	// Delete removes a single backup. It must not return an error if the
+	// backup is already gone.
+	Delete(name string) error
+	// Cleanup is called when the cluster is being deleted entirely. The
+	// object-store providers (S3, GCS, ABS) leave the bucket or container
+	// and its backups in place, even one that Setup created; the
+	// PersistentVolume provider removes its whole backup directory.
+	Cleanup() error
+}
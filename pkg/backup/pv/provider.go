@@ -0,0 +1,104 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pv implements backup.BackupProvider against a directory backed by
+// a mounted PersistentVolume, for clusters that don't have access to an
+// object store.
+package pv
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/etcd-operator/pkg/backup"
+
+	"golang.org/x/net/context"
+)
+
+// Context holds the PersistentVolume configuration for a cluster's backups.
+type Context struct {
+	// Dir is the path the PVC is mounted at.
+	Dir string
+}
+
+// Provider implements backup.BackupProvider against a directory on a
+// mounted PersistentVolume.
+type Provider struct {
+	Context
+}
+
+func New(ctx Context) *Provider {
+	return &Provider{Context: ctx}
+}
+
+func (p *Provider) Setup(ctx context.Context) error {
+	return os.MkdirAll(p.Dir, 0700)
+}
+
+func (p *Provider) Upload(name string, r io.Reader) error {
+	f, err := os.Create(p.pathFor(name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func (p *Provider) Download(name string) (io.ReadCloser, error) {
+	return os.Open(p.pathFor(name))
+}
+
+func (p *Provider) List() ([]backup.BackupInfo, error) {
+	entries, err := ioutil.ReadDir(p.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]backup.BackupInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		infos = append(infos, backup.BackupInfo{
+			Name:      e.Name(),
+			Size:      e.Size(),
+			CreatedAt: e.ModTime(),
+		})
+	}
+	return infos, nil
+}
+
+func (p *Provider) Delete(name string) error {
+	err := os.Remove(p.pathFor(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Cleanup removes the entire backup directory: on a PV, unlike an object
+// store, that directory is ours alone to manage.
+func (p *Provider) Cleanup() error {
+	return os.RemoveAll(p.Dir)
+}
+
+func (p *Provider) pathFor(name string) string {
+	return filepath.Join(p.Dir, name)
+}
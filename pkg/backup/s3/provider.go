@@ -0,0 +1,116 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s3
+
+import (
+	"io"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/backup"
+	"github.com/coreos/etcd-operator/pkg/backup/s3/s3config"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"golang.org/x/net/context"
+)
+
+// Provider implements backup.BackupProvider against an S3-compatible bucket.
+type Provider struct {
+	s3config.S3Context
+
+	s3cli *s3.S3
+}
+
+// New returns a Provider for the given S3 context. It does not talk to S3
+// until Setup is called.
+func New(ctx s3config.S3Context) *Provider {
+	return &Provider{S3Context: ctx}
+}
+
+func (p *Provider) Setup(ctx context.Context) error {
+	p.s3cli = s3.New(p.S3Context.Session())
+
+	_, err := p.s3cli.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(p.S3Bucket)})
+	if err == nil {
+		return nil
+	}
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "NotFound" {
+		return err
+	}
+
+	_, err = p.s3cli.CreateBucketWithContext(ctx, &s3.CreateBucketInput{Bucket: aws.String(p.S3Bucket)})
+	return err
+}
+
+func (p *Provider) Upload(name string, r io.Reader) error {
+	uploader := s3manager.NewUploaderWithClient(p.s3cli)
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket: aws.String(p.S3Bucket),
+		Key:    aws.String(p.keyFor(name)),
+		Body:   r,
+	})
+	return err
+}
+
+func (p *Provider) Download(name string) (io.ReadCloser, error) {
+	out, err := p.s3cli.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(p.S3Bucket),
+		Key:    aws.String(p.keyFor(name)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (p *Provider) List() ([]backup.BackupInfo, error) {
+	out, err := p.s3cli.ListObjects(&s3.ListObjectsInput{
+		Bucket: aws.String(p.S3Bucket),
+		Prefix: aws.String(p.S3Prefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]backup.BackupInfo, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		infos = append(infos, backup.BackupInfo{
+			Name:      strings.TrimPrefix(aws.StringValue(obj.Key), p.S3Prefix+"/"),
+			Size:      aws.Int64Value(obj.Size),
+			CreatedAt: aws.TimeValue(obj.LastModified),
+		})
+	}
+	return infos, nil
+}
+
+func (p *Provider) Delete(name string) error {
+	_, err := p.s3cli.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(p.S3Bucket),
+		Key:    aws.String(p.keyFor(name)),
+	})
+	return err
+}
+
+// Cleanup is a no-op: we never own the bucket's lifecycle, only the objects
+// we wrote into it, and Delete already covers those.
+func (p *Provider) Cleanup() error {
+	return nil
+}
+
+func (p *Provider) keyFor(name string) string {
+	return p.S3Prefix + "/" + name
+}
@@ -0,0 +1,106 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/coreos/etcd-operator/pkg/backup"
+	"github.com/coreos/etcd-operator/pkg/backup/abs"
+	"github.com/coreos/etcd-operator/pkg/backup/gcs"
+	"github.com/coreos/etcd-operator/pkg/backup/pv"
+	"github.com/coreos/etcd-operator/pkg/backup/s3"
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// backupManager drives whichever backup.BackupProvider the cluster's
+// Spec.Backup.StorageType selects. It owns no storage-specific logic itself:
+// that all lives behind the BackupProvider interface.
+type backupManager struct {
+	logger *logrus.Entry
+
+	provider backup.BackupProvider
+}
+
+// newBackupManager picks the BackupProvider matching cl.Spec.Backup.StorageType
+// out of the operator's per-provider credential config. It mirrors the
+// tidb-operator backup-manager's dispatch-behind-an-interface pattern: the
+// rest of the cluster package only ever talks to the resulting provider.
+func newBackupManager(c Config, cl *spec.Cluster, l *logrus.Entry) (*backupManager, error) {
+	provider, err := newBackupProvider(c, cl)
+	if err != nil {
+		return nil, err
+	}
+	return &backupManager{logger: l, provider: provider}, nil
+}
+
+func newBackupProvider(c Config, cl *spec.Cluster) (backup.BackupProvider, error) {
+	st := cl.Spec.Backup.StorageType
+	switch st {
+	case "", backup.StorageTypeS3:
+		return s3.New(c.BackupProviderConfig.S3), nil
+	case backup.StorageTypeGCS:
+		return gcs.New(c.BackupProviderConfig.GCS), nil
+	case backup.StorageTypeABS:
+		return abs.New(c.BackupProviderConfig.ABS), nil
+	case backup.StorageTypePersistentVolume:
+		return pv.New(c.BackupProviderConfig.PV), nil
+	default:
+		return nil, fmt.Errorf("unknown backup storage type %q", st)
+	}
+}
+
+func (bm *backupManager) setup() error {
+	if err := bm.provider.Setup(context.Background()); err != nil {
+		return fmt.Errorf("backup manager: failed to set up provider: %v", err)
+	}
+	return nil
+}
+
+// saveSnap uploads a new backup blob under name.
+func (bm *backupManager) saveSnap(name string, r io.Reader) error {
+	return bm.provider.Upload(name, r)
+}
+
+// openLatest returns the most recently created backup, or errNoBackupExist
+// if none are available yet.
+func (bm *backupManager) openLatest() (io.ReadCloser, error) {
+	infos, err := bm.provider.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, errNoBackupExist
+	}
+
+	latest := infos[0]
+	for _, info := range infos[1:] {
+		if info.CreatedAt.After(latest.CreatedAt) {
+			latest = info
+		}
+	}
+	return bm.provider.Download(latest.Name)
+}
+
+// cleanup runs the provider's Cleanup, for use when the cluster itself is
+// deleted. For the object-store providers this leaves the bucket or
+// container and its backups in place.
+func (bm *backupManager) cleanup() error {
+	return bm.provider.Cleanup()
+}
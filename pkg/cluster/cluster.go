@@ -22,6 +22,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/coreos/etcd-operator/pkg/backup"
+	"github.com/coreos/etcd-operator/pkg/backup/abs"
+	"github.com/coreos/etcd-operator/pkg/backup/gcs"
+	"github.com/coreos/etcd-operator/pkg/backup/pv"
+	"github.com/coreos/etcd-operator/pkg/backup/s3"
 	"github.com/coreos/etcd-operator/pkg/backup/s3/s3config"
 	"github.com/coreos/etcd-operator/pkg/garbagecollection"
 	"github.com/coreos/etcd-operator/pkg/spec"
@@ -36,29 +41,38 @@ import (
 	apierrors "k8s.io/client-go/1.5/pkg/api/errors"
 	"k8s.io/client-go/1.5/pkg/api/meta/metatypes"
 	"k8s.io/client-go/1.5/pkg/api/v1"
+	"k8s.io/client-go/1.5/pkg/client/cache"
+	"k8s.io/client-go/1.5/pkg/runtime"
+	"k8s.io/client-go/1.5/pkg/util/wait"
+	"k8s.io/client-go/1.5/pkg/util/workqueue"
+	"k8s.io/client-go/1.5/pkg/watch"
 )
 
 var (
-	reconcileInterval         = 8 * time.Second
+	// resyncPeriod is the safety-net resync interval for the pod informer.
+	// Normal reconciliation is driven by informer events on the workqueue.
+	resyncPeriod              = 8 * time.Second
 	podTerminationGracePeriod = int64(5)
 )
 
-type clusterEventType string
-
 const (
-	eventDeleteCluster clusterEventType = "Delete"
-	eventModifyCluster clusterEventType = "Modify"
+	maxReconcileRetries = 8
 )
 
-type clusterEvent struct {
-	typ     clusterEventType
-	cluster *spec.Cluster
+// BackupProviderConfig is a discriminated union of the credentials/settings
+// needed by each concrete backup.BackupProvider. Only the field matching a
+// given cluster's Spec.Backup.StorageType is read.
+type BackupProviderConfig struct {
+	S3  s3config.S3Context
+	GCS gcs.Context
+	ABS abs.Context
+	PV  pv.Context
 }
 
 type Config struct {
 	PVProvisioner  string
 	ServiceAccount string
-	s3config.S3Context
+	BackupProviderConfig
 
 	MasterHost string
 	KubeCli    kubernetes.Interface
@@ -76,8 +90,23 @@ type Cluster struct {
 	status        spec.ClusterStatus
 	memberCounter int
 
-	eventCh chan *clusterEvent
-	stopCh  chan struct{}
+	// queue carries the cluster's own key (namespace/name). There is only ever
+	// one item in flight for a given cluster, but we go through a rate-limiting
+	// workqueue so that transient reconcile errors get an exponential backoff
+	// instead of busy-looping.
+	queue workqueue.RateLimitingInterface
+
+	// updateMu guards pendingSpec, which is set by Update and consumed by the
+	// worker the next time it processes the cluster's key.
+	updateMu    sync.Mutex
+	pendingSpec *spec.Cluster
+	deleted     bool
+
+	podInformer cache.SharedIndexInformer
+
+	hc *healthChecker
+
+	stopCh chan struct{}
 
 	// members repsersents the members in the etcd cluster.
 	// the name of the member is the the name of the pod the member
@@ -90,17 +119,24 @@ type Cluster struct {
 	gc *garbagecollection.GC
 }
 
+// key returns the workqueue key used for this cluster.
+func (c *Cluster) key() string {
+	return c.cluster.Metadata.Namespace + "/" + c.cluster.Metadata.Name
+}
+
 func New(config Config, cl *spec.Cluster, stopC <-chan struct{}, wg *sync.WaitGroup) *Cluster {
 	lg := logrus.WithField("pkg", "cluster").WithField("cluster-name", cl.Metadata.Name)
 	c := &Cluster{
 		logger:  lg,
 		config:  config,
 		cluster: cl,
-		eventCh: make(chan *clusterEvent, 100),
+		queue:   workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 		stopCh:  make(chan struct{}),
 		status:  cl.Status.Copy(),
 		gc:      garbagecollection.New(config.KubeCli, cl.Metadata.Namespace),
 	}
+	c.podInformer = c.newPodInformer()
+	c.hc = newHealthChecker(cl.Metadata.Name)
 
 	wg.Add(1)
 	go func() {
@@ -166,8 +202,10 @@ func (c *Cluster) create() error {
 
 	if c.bm != nil {
 		if err := c.bm.setup(); err != nil {
+			c.status.SetBackupHealthy(false, "BackupSetupFailed", err.Error())
 			return err
 		}
+		c.status.SetBackupHealthy(true, "BackupConfigured", "")
 	}
 
 	if c.cluster.Spec.Restore == nil {
@@ -206,31 +244,66 @@ func (c *Cluster) prepareSeedMember() error {
 }
 
 func (c *Cluster) Delete() {
-	c.send(&clusterEvent{typ: eventDeleteCluster})
+	c.updateMu.Lock()
+	c.deleted = true
+	c.updateMu.Unlock()
+	c.enqueue()
 }
 
-func (c *Cluster) send(ev *clusterEvent) {
+func (c *Cluster) Update(cl *spec.Cluster) {
+	c.updateMu.Lock()
+	c.pendingSpec = cl
+	c.updateMu.Unlock()
+	c.enqueue()
+}
+
+// enqueue adds the cluster's key to the workqueue. It never blocks: the
+// workqueue dedupes identical keys, so a burst of informer events collapses
+// into a single pending reconcile.
+func (c *Cluster) enqueue() {
 	select {
-	case c.eventCh <- ev:
-		l, ecap := len(c.eventCh), cap(c.eventCh)
-		if l > int(float64(ecap)*0.8) {
-			c.logger.Warningf("eventCh buffer is almost full [%d/%d]", l, ecap)
-		}
 	case <-c.stopCh:
+	default:
+		c.queue.Add(c.key())
 	}
 }
 
-func (c *Cluster) run(stopC <-chan struct{}) {
-	clusterFailed := false
+// newPodInformer builds a SharedIndexInformer over the pods owned by this
+// cluster, following the workqueue-backed controller pattern used by the
+// kube deploymentconfig-controller: informer event handlers only enqueue the
+// cluster key, all actual work happens in the worker goroutine.
+func (c *Cluster) newPodInformer() cache.SharedIndexInformer {
+	lw := &cache.ListWatch{
+		ListFunc: func(options api.ListOptions) (runtime.Object, error) {
+			opts := k8sutil.ClusterListOpt(c.cluster.Metadata.Name)
+			return c.config.KubeCli.Core().Pods(c.cluster.Metadata.Namespace).List(opts)
+		},
+		WatchFunc: func(options api.ListOptions) (watch.Interface, error) {
+			opts := k8sutil.ClusterListOpt(c.cluster.Metadata.Name)
+			return c.config.KubeCli.Core().Pods(c.cluster.Metadata.Namespace).Watch(opts)
+		},
+	}
 
-	defer func() {
-		if clusterFailed {
-			c.reportFailedStatus()
+	informer := cache.NewSharedIndexInformer(lw, &v1.Pod{}, resyncPeriod, cache.Indexers{})
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueue() },
+		UpdateFunc: func(oldObj, newObj interface{}) { c.enqueue() },
+		DeleteFunc: func(obj interface{}) { c.enqueue() },
+	})
+	return informer
+}
 
-			c.logger.Infof("deleting the failed cluster")
-			c.delete()
-		}
+func (c *Cluster) run(stopC <-chan struct{}) {
+	// workerDone carries whether the worker goroutine stopped because the
+	// cluster failed or was deleted, as opposed to the operator shutting
+	// down. run() must act on it and return immediately, rather than
+	// waiting on the operator-wide stopC like the rest of this method does:
+	// otherwise a deleted cluster's pods/services/PVCs/backups never get
+	// garbage collected until the whole operator exits.
+	workerDone := make(chan bool, 1)
 
+	defer func() {
+		c.queue.ShutDown()
 		close(c.stopCh)
 	}()
 
@@ -240,82 +313,168 @@ func (c *Cluster) run(stopC <-chan struct{}) {
 	}
 	c.logger.Infof("start running...")
 
-	var rerr error
+	// The informer, resync ticker, and worker are all tied to c.stopCh
+	// (closed above on return), not the operator-wide stopC: otherwise they
+	// would keep running for the life of the process after this cluster is
+	// done, well past the point its own run loop has exited.
+	go c.podInformer.Run(c.stopCh)
+	go c.hc.run(c.stopCh)
+	if !cache.WaitForCacheSync(c.stopCh, c.podInformer.HasSynced) {
+		c.logger.Errorf("failed to sync pod informer cache")
+		return
+	}
+
+	// Periodic resync as a safety net: events drive normal reconciliation,
+	// this just guards against a missed or coalesced informer event.
+	go wait.Until(c.enqueue, resyncPeriod, c.stopCh)
+
+	go c.runWorker(workerDone)
+
+	select {
+	case <-stopC:
+	case failed := <-workerDone:
+		if failed {
+			c.reportFailedStatus()
+
+			c.logger.Infof("deleting the failed cluster")
+			c.delete()
+		}
+	}
+}
+
+// runWorker drains the workqueue until it is shut down, then reports via
+// done whether the shutdown was triggered by cluster failure/deletion.
+func (c *Cluster) runWorker(done chan<- bool) {
 	for {
-		select {
-		case <-stopC:
+		cont, failed := c.processNextWorkItem()
+		if !cont {
+			done <- failed
 			return
-		case event := <-c.eventCh:
-			switch event.typ {
-			case eventModifyCluster:
-				if isSpecEqual(event.cluster.Spec, c.cluster.Spec) {
-					break
-				}
-				// TODO: we can't handle another upgrade while an upgrade is in progress
-				c.logger.Infof("spec update: from: %v to: %v", c.cluster.Spec, event.cluster.Spec)
-				c.cluster = event.cluster
-
-			case eventDeleteCluster:
-				c.logger.Infof("cluster is deleted by the user")
-				clusterFailed = true
-				return
-			}
+		}
+	}
+}
 
-		case <-time.After(reconcileInterval):
-			if c.cluster.Spec.Paused {
-				c.status.PauseControl()
-				c.logger.Infof("control is paused, skipping reconcilation")
-				continue
-			} else {
-				c.status.Control()
-			}
+// processNextWorkItem pops one key off the workqueue, reconciles, and
+// reports the outcome back to the rate limiter: AddRateLimited on a
+// transient error (so the next attempt backs off), Forget on success.
+// cont is false once the queue has been shut down; failed reports whether
+// that shutdown was caused by cluster failure/deletion.
+func (c *Cluster) processNextWorkItem() (cont bool, failed bool) {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false, false
+	}
+	defer c.queue.Done(key)
 
-			running, pending, err := c.pollPods()
-			if err != nil {
-				c.logger.Errorf("fail to poll pods: %v", err)
-				continue
-			}
-			if len(pending) > 0 {
-				c.logger.Infof("skip reconciliation: running (%v), pending (%v)", k8sutil.GetPodNames(running), k8sutil.GetPodNames(pending))
-				continue
-			}
-			if len(running) == 0 {
-				c.logger.Warningf("all etcd pods are dead. Trying to recover from a previous backup")
-				rerr = c.disasterRecovery(nil)
-				if rerr != nil {
-					c.logger.Errorf("fail to do disaster recovery: %v", rerr)
-				}
-				// On normal recovery case, we need backoff. On error case, this could be either backoff or leading to cluster delete.
-				break
-			}
+	rerr := c.sync()
+	if rerr == nil {
+		c.queue.Forget(key)
+		return true, false
+	}
 
-			// On controller restore, we could have "members == nil"
-			if rerr != nil || c.members == nil {
-				rerr = c.updateMembers(podsToMemberSet(running, c.cluster.Spec.SelfHosted))
-				if rerr != nil {
-					c.logger.Errorf("failed to update members: %v", rerr)
-					break
-				}
-			}
-			rerr = c.reconcile(running)
-			if rerr != nil {
-				c.logger.Errorf("failed to reconcile: %v", rerr)
-				break
-			}
+	if rerr == errClusterDeleted {
+		c.logger.Infof("cluster is deleted by the user")
+		c.queue.Forget(key)
+		c.queue.ShutDown()
+		return false, true
+	}
 
-			if err := c.updateStatus(); err != nil {
-				c.logger.Warningf("failed to update TPR status: %v", err)
-			}
+	if isFatalError(rerr) {
+		c.status.SetReason(rerr.Error())
+		c.logger.Errorf("cluster failed: %v", rerr)
+		c.queue.Forget(key)
+		c.queue.ShutDown()
+		return false, true
+	}
+
+	if c.queue.NumRequeues(key) < maxReconcileRetries {
+		c.logger.Errorf("error syncing cluster (will retry): %v", rerr)
+		c.queue.AddRateLimited(key)
+	} else {
+		c.logger.Errorf("error syncing cluster (giving up after %d retries): %v", maxReconcileRetries, rerr)
+		c.queue.Forget(key)
+	}
+	return true, false
+}
+
+// sync applies any pending spec update and runs one reconciliation pass.
+// It is the workqueue-driven replacement for the old poll-and-reconcile tick.
+func (c *Cluster) sync() error {
+	c.updateMu.Lock()
+	deleted := c.deleted
+	pending := c.pendingSpec
+	c.pendingSpec = nil
+	c.updateMu.Unlock()
+
+	if deleted {
+		return errClusterDeleted
+	}
+
+	if pending != nil && !isSpecEqual(pending.Spec, c.cluster.Spec) {
+		// TODO: we can't handle another upgrade while an upgrade is in progress
+		if pending.Spec.Version != c.cluster.Spec.Version {
+			// There is no per-member rolling upgrade in this tree yet (no
+			// upgradeOneMember), so this only records that a version bump was
+			// requested; it does not reflect an in-progress member-by-member
+			// roll.
+			c.status.AppendUpgradingCondition(c.cluster.Spec.Version, pending.Spec.Version)
 		}
+		c.logger.Infof("spec update: from: %v to: %v", c.cluster.Spec, pending.Spec)
+		c.cluster = pending
+	}
 
-		if isFatalError(rerr) {
-			clusterFailed = true
-			c.status.SetReason(rerr.Error())
+	if c.cluster.Spec.Paused {
+		c.status.PauseControl()
+		c.logger.Infof("control is paused, skipping reconcilation")
+		return nil
+	}
+	c.status.Control()
 
-			c.logger.Errorf("cluster failed: %v", rerr)
-			return
+	running, pending2, err := c.pollPods()
+	if err != nil {
+		return fmt.Errorf("fail to poll pods: %v", err)
+	}
+	if len(pending2) > 0 {
+		c.logger.Infof("skip reconciliation: running (%v), pending (%v)", k8sutil.GetPodNames(running), k8sutil.GetPodNames(pending2))
+		return nil
+	}
+	if len(running) == 0 {
+		c.logger.Warningf("all etcd pods are dead. Trying to recover from a previous backup")
+		c.status.AppendRecoveringCondition()
+		if err := c.disasterRecovery(nil); err != nil {
+			c.logger.Errorf("fail to do disaster recovery: %v", err)
+			return err
+		}
+		return nil
+	}
+
+	// On controller restore, we could have "members == nil"
+	if c.members == nil {
+		if err := c.updateMembers(podsToMemberSet(running, c.cluster.Spec.SelfHosted)); err != nil {
+			return fmt.Errorf("failed to update members: %v", err)
 		}
 	}
+
+	// Hand the health checker a snapshot rather than letting it read
+	// c.members itself: c.members is only ever touched from this worker
+	// goroutine, and the health checker runs on its own.
+	c.hc.SetTargets(c.memberClientURLs())
+
+	// Members with unhealthyThreshold+ consecutive failed probes are
+	// removed here, before reconcile runs, so reconcile's normal
+	// fewer-running-than-Spec.Size handling recreates them fresh instead of
+	// leaving a wedged member in place indefinitely.
+	running = c.replaceUnhealthyMembers(running)
+
+	if err := c.reconcile(running); err != nil {
+		return fmt.Errorf("failed to reconcile: %v", err)
+	}
+	c.hc.applyAvailableCondition(&c.status)
+
+	if err := c.updateStatus(); err != nil {
+		c.logger.Warningf("failed to update TPR status: %v", err)
+	}
+	return nil
 }
 
 func isSpecEqual(s1, s2 spec.ClusterSpec) bool {
@@ -325,6 +484,11 @@ func isSpecEqual(s1, s2 spec.ClusterSpec) bool {
 	return true
 }
 
+// errClusterDeleted is returned by sync when Delete has been called on the
+// cluster; it is handled before the generic fatal-error check since it isn't
+// a reconciliation failure.
+var errClusterDeleted = errors.New("cluster deleted")
+
 func isFatalError(err error) bool {
 	switch err {
 	case errNoBackupExist, errInvalidMemberName, errUnexpectedUnreadyMember:
@@ -359,16 +523,13 @@ func (c *Cluster) restoreSeedMember() error {
 	return c.startSeedMember(true)
 }
 
-func (c *Cluster) Update(cl *spec.Cluster) {
-	c.send(&clusterEvent{
-		typ:     eventModifyCluster,
-		cluster: cl,
-	})
-}
-
 func (c *Cluster) delete() {
 	c.gc.CollectCluster(c.cluster.Metadata.Name, garbagecollection.NullUID)
 
+	if err := c.deleteAllMemberPVCs(); err != nil {
+		c.logger.Errorf("cluster deletion: failed to delete member PVCs: %v", err)
+	}
+
 	if c.bm != nil {
 		if err := c.bm.cleanup(); err != nil {
 			c.logger.Errorf("cluster deletion: backup manager failed to cleanup: %v", err)
@@ -401,10 +562,31 @@ func (c *Cluster) createPodAndService(members etcdutil.MemberSet, m *etcdutil.Me
 		token = uuid.New()
 	}
 
-	pod := k8sutil.MakeEtcdPod(m, members.PeerURLPairs(), c.cluster.Metadata.Name, state, token, c.cluster.Spec, c.cluster.AsOwner())
+	pod, err := k8sutil.MakeEtcdPod(m, members.PeerURLPairs(), c.cluster.Metadata.Name, state, token, c.cluster.Spec, c.cluster.AsOwner())
+	if err != nil {
+		return fmt.Errorf("failed to make pod for member (%s): %v", m.Name, err)
+	}
 	if needRecovery {
 		k8sutil.AddRecoveryToPod(pod, c.cluster.Metadata.Name, m.Name, token, c.cluster.Spec)
 	}
+
+	if pvcSpec := c.cluster.Spec.Pod.PersistentVolumeClaimSpec; pvcSpec != nil {
+		pvc, err := c.createMemberPVC(m, *pvcSpec)
+		if err != nil {
+			return fmt.Errorf("failed to create PVC for member (%s): %v", m.Name, err)
+		}
+		k8sutil.AddEtcdVolumeToPod(pod, pvc.Name)
+		// The PVC, not the pod, is the thing worth keeping around: record it
+		// on the pod's owner references so that if the PVC ever goes away,
+		// the (now dataless) pod is cleaned up with it.
+		pod.OwnerReferences = append(pod.OwnerReferences, metatypes.OwnerReference{
+			APIVersion: "v1",
+			Kind:       "PersistentVolumeClaim",
+			Name:       pvc.Name,
+			UID:        pvc.UID,
+		})
+	}
+
 	p, err := c.config.KubeCli.Core().Pods(c.cluster.Metadata.Namespace).Create(pod)
 	if err != nil {
 		return err
@@ -447,19 +629,77 @@ func (c *Cluster) removePodAndService(name string) error {
 			return err
 		}
 	}
+	c.status.AppendRemoveMemberCondition(name, len(c.members))
+
+	if !c.shouldRetainPVCOnRemove() {
+		if err := c.deleteMemberPVC(name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (c *Cluster) pollPods() ([]*v1.Pod, []*v1.Pod, error) {
-	podList, err := c.config.KubeCli.Core().Pods(c.cluster.Metadata.Namespace).List(k8sutil.ClusterListOpt(c.cluster.Metadata.Name))
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to list running pods: %v", err)
+// memberClientURLs returns the client endpoint to probe for each currently
+// known member, keyed by member name. It feeds the health checker, which
+// runs independently of the reconcile worker goroutine.
+func (c *Cluster) memberClientURLs() map[string]string {
+	urls := make(map[string]string, len(c.members))
+	for name, m := range c.members {
+		urls[name] = m.ClientURL()
+	}
+	return urls
+}
+
+// replaceUnhealthyMembers removes the pod/service (and, per PVCDeletePolicy,
+// the PVC) for every member the health checker has marked unhealthy, and
+// drops them from running and c.members so reconcile treats them as gone
+// and recreates them.
+func (c *Cluster) replaceUnhealthyMembers(running []*v1.Pod) []*v1.Pod {
+	unhealthy := c.hc.UnhealthyMembers()
+	if len(unhealthy) == 0 {
+		return running
+	}
+	unhealthySet := make(map[string]bool, len(unhealthy))
+	for _, name := range unhealthy {
+		unhealthySet[name] = true
 	}
 
+	filtered := make([]*v1.Pod, 0, len(running))
+	for _, pod := range running {
+		if !unhealthySet[pod.Name] {
+			filtered = append(filtered, pod)
+			continue
+		}
+
+		c.logger.Warningf("replacing member %s after %d consecutive failed health probes", pod.Name, unhealthyThreshold)
+		// Drop the member first so the size removePodAndService records in
+		// the Scaling condition no longer counts it.
+		m, known := c.members[pod.Name]
+		delete(c.members, pod.Name)
+		if err := c.removePodAndService(pod.Name); err != nil {
+			c.logger.Errorf("failed to remove unhealthy member %s: %v", pod.Name, err)
+			if known {
+				c.members[pod.Name] = m
+			}
+			filtered = append(filtered, pod)
+			continue
+		}
+	}
+	return filtered
+}
+
+// pollPods reads the pod informer's local cache rather than hitting the
+// API server directly, now that the informer keeps it up to date for us.
+func (c *Cluster) pollPods() ([]*v1.Pod, []*v1.Pod, error) {
+	objs := c.podInformer.GetStore().List()
+
 	var running []*v1.Pod
 	var pending []*v1.Pod
-	for i := range podList.Items {
-		pod := &podList.Items[i]
+	for i := range objs {
+		pod, ok := objs[i].(*v1.Pod)
+		if !ok {
+			continue
+		}
 		if len(pod.OwnerReferences) < 1 {
 			c.logger.Warningf("pollPods: ignore pod %v: no owner", pod.Name)
 			continue
@@ -0,0 +1,310 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const (
+	healthCheckInterval = 5 * time.Second
+	healthCheckTimeout  = 5 * time.Second
+
+	// unhealthyThreshold is how many consecutive failed probes a member
+	// must accumulate before reconcile is allowed to replace it. A single
+	// dropped health check (a blip during a rolling upgrade, a slow GC
+	// pause) must not trigger a replacement.
+	unhealthyThreshold = 3
+)
+
+var (
+	memberHasLeaderGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "health",
+		Name:      "member_has_leader",
+		Help:      "Whether the member sees a cluster leader (1) or not (0)",
+	}, []string{"cluster", "member"})
+
+	memberIsLeaderGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "health",
+		Name:      "member_is_leader",
+		Help:      "Whether the member is itself the cluster leader",
+	}, []string{"cluster", "member"})
+
+	memberDBSizeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "health",
+		Name:      "member_db_size_bytes",
+		Help:      "The etcd member's on-disk database size in bytes",
+	}, []string{"cluster", "member"})
+
+	memberAlarmsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "etcd_operator",
+		Subsystem: "health",
+		Name:      "member_alarm_count",
+		Help:      "The number of active alarms raised on the member",
+	}, []string{"cluster", "member"})
+)
+
+func init() {
+	prometheus.MustRegister(memberHasLeaderGauge, memberIsLeaderGauge, memberDBSizeGauge, memberAlarmsGauge)
+}
+
+// MemberHealth is the last observed health of one etcd member.
+type MemberHealth struct {
+	Name                string
+	Healthy             bool
+	HasLeader           bool
+	IsLeader            bool
+	DBSizeBytes         int64
+	Alarms              []string
+	ConsecutiveFailures int
+	LastProbeTime       time.Time
+}
+
+// healthChecker runs a background goroutine per cluster that probes every
+// target member with the etcd v3 client's Status and AlarmList calls,
+// recording leader identity, DB size, and active alarms. It does not query
+// the HTTP /health endpoint or MemberList. The reconcile worker's sync pass
+// consults the resulting cache (to replace members past unhealthyThreshold
+// and to set the Available and Alarm conditions) instead of probing etcd
+// itself, so a slow or flapping member doesn't block the whole reconcile
+// loop on a client-side timeout. disasterRecovery does not consult it.
+type healthChecker struct {
+	clusterName string
+
+	mu      sync.RWMutex
+	members map[string]*MemberHealth
+
+	// targets is the set of member client endpoints to probe, keyed by
+	// member name. It is updated by SetTargets rather than read live off
+	// Cluster.members: Cluster.members is owned by the reconcile worker
+	// goroutine and is not safe to share with this independent goroutine
+	// without its own synchronization.
+	targetsMu sync.RWMutex
+	targets   map[string]string
+}
+
+func newHealthChecker(clusterName string) *healthChecker {
+	return &healthChecker{
+		clusterName: clusterName,
+		members:     make(map[string]*MemberHealth),
+		targets:     make(map[string]string),
+	}
+}
+
+// SetTargets replaces the set of member endpoints to probe. The reconcile
+// worker goroutine calls this once per sync pass with a snapshot of the
+// current membership, so the health checker never touches Cluster.members
+// itself.
+func (hc *healthChecker) SetTargets(clientURLs map[string]string) {
+	hc.targetsMu.Lock()
+	hc.targets = clientURLs
+	hc.targetsMu.Unlock()
+}
+
+func (hc *healthChecker) getTargets() map[string]string {
+	hc.targetsMu.RLock()
+	defer hc.targetsMu.RUnlock()
+	return hc.targets
+}
+
+// run polls every member in the current target set every healthCheckInterval
+// until stopC is closed.
+func (hc *healthChecker) run(stopC <-chan struct{}) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopC:
+			return
+		case <-ticker.C:
+			hc.probeAll(hc.getTargets())
+		}
+	}
+}
+
+func (hc *healthChecker) probeAll(clientURLs map[string]string) {
+	for name, endpoint := range clientURLs {
+		hc.probeOne(name, endpoint)
+	}
+}
+
+func (hc *healthChecker) probeOne(name, endpoint string) {
+	mh := hc.snapshot(name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{endpoint}, DialTimeout: healthCheckTimeout})
+	if err != nil {
+		hc.recordFailure(mh, name)
+		return
+	}
+	defer cli.Close()
+
+	statusResp, err := cli.Status(ctx, endpoint)
+	if err != nil {
+		hc.recordFailure(mh, name)
+		return
+	}
+
+	alarmResp, err := cli.AlarmList(ctx)
+	if err != nil {
+		hc.recordFailure(mh, name)
+		return
+	}
+
+	mh.Healthy = true
+	mh.ConsecutiveFailures = 0
+	mh.HasLeader = statusResp.Leader != 0
+	mh.IsLeader = statusResp.Leader == statusResp.Header.MemberId
+	mh.DBSizeBytes = statusResp.DbSize
+	mh.Alarms = mh.Alarms[:0]
+	for _, a := range alarmResp.Alarms {
+		mh.Alarms = append(mh.Alarms, a.Alarm.String())
+	}
+	mh.LastProbeTime = time.Now()
+
+	hc.store(name, mh)
+	hc.updateGauges(name, mh)
+}
+
+func (hc *healthChecker) recordFailure(mh *MemberHealth, name string) {
+	mh.ConsecutiveFailures++
+	mh.Healthy = mh.ConsecutiveFailures < unhealthyThreshold
+	mh.LastProbeTime = time.Now()
+	hc.store(name, mh)
+	hc.updateGauges(name, mh)
+}
+
+func (hc *healthChecker) updateGauges(name string, mh *MemberHealth) {
+	labels := prometheus.Labels{"cluster": hc.clusterName, "member": name}
+	setBool := func(gv *prometheus.GaugeVec, v bool) {
+		f := 0.0
+		if v {
+			f = 1.0
+		}
+		gv.With(labels).Set(f)
+	}
+	setBool(memberHasLeaderGauge, mh.HasLeader)
+	setBool(memberIsLeaderGauge, mh.IsLeader)
+	memberDBSizeGauge.With(labels).Set(float64(mh.DBSizeBytes))
+	memberAlarmsGauge.With(labels).Set(float64(len(mh.Alarms)))
+}
+
+func (hc *healthChecker) snapshot(name string) *MemberHealth {
+	hc.mu.RLock()
+	mh, ok := hc.members[name]
+	hc.mu.RUnlock()
+	if ok {
+		cp := *mh
+		return &cp
+	}
+	return &MemberHealth{Name: name}
+}
+
+func (hc *healthChecker) store(name string, mh *MemberHealth) {
+	hc.mu.Lock()
+	hc.members[name] = mh
+	hc.mu.Unlock()
+}
+
+// Get returns the last observed health for a member, or (MemberHealth{},
+// false) if it has never been probed.
+func (hc *healthChecker) Get(name string) (MemberHealth, bool) {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+	mh, ok := hc.members[name]
+	if !ok {
+		return MemberHealth{}, false
+	}
+	return *mh, true
+}
+
+// UnhealthyMembers returns the members that have failed at least
+// unhealthyThreshold consecutive probes, and are therefore eligible for
+// replacement.
+func (hc *healthChecker) UnhealthyMembers() []string {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	var names []string
+	for name, mh := range hc.members {
+		if mh.ConsecutiveFailures >= unhealthyThreshold {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// applyAvailableCondition folds the current leader-election, db-size, and
+// alarm view into the cluster's Available and Alarm conditions. It's called
+// from the reconcile worker goroutine, the only place c.status is mutated,
+// so no locking is needed there beyond healthChecker's own.
+func (hc *healthChecker) applyAvailableCondition(status *spec.ClusterStatus) {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	var leader string
+	var hasLeader bool
+	var totalDBSize int64
+	var alarms []string
+	for name, mh := range hc.members {
+		if mh.IsLeader {
+			leader = name
+		}
+		if mh.HasLeader {
+			hasLeader = true
+		}
+		totalDBSize += mh.DBSizeBytes
+		if len(mh.Alarms) > 0 {
+			alarms = append(alarms, fmt.Sprintf("%s: %s", name, strings.Join(mh.Alarms, ",")))
+		}
+	}
+
+	availableStatus := spec.ConditionFalse
+	if hasLeader {
+		availableStatus = spec.ConditionTrue
+	}
+	status.SetCondition(spec.ClusterCondition{
+		Type:    spec.ClusterConditionAvailable,
+		Status:  availableStatus,
+		Reason:  "LeaderElection",
+		Message: fmt.Sprintf("leader=%s dbSizeBytes=%d", leader, totalDBSize),
+	})
+
+	alarmStatus := spec.ConditionFalse
+	if len(alarms) > 0 {
+		alarmStatus = spec.ConditionTrue
+	}
+	status.SetCondition(spec.ClusterCondition{
+		Type:    spec.ClusterConditionAlarm,
+		Status:  alarmStatus,
+		Reason:  "MemberAlarm",
+		Message: strings.Join(alarms, "; "),
+	})
+}
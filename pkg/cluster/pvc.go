@@ -0,0 +1,99 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+	"github.com/coreos/etcd-operator/pkg/util/k8sutil"
+
+	"k8s.io/client-go/1.5/pkg/api/v1"
+)
+
+// createMemberPVC creates (or, on a retry, adopts) the PVC backing member
+// m's data directory. The PVC is named after the member so it survives pod
+// restarts and replacements.
+func (c *Cluster) createMemberPVC(m *etcdutil.Member, pvcSpec v1.PersistentVolumeClaimSpec) (*v1.PersistentVolumeClaim, error) {
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:   m.Name,
+			Labels: k8sutil.LabelsForCluster(c.cluster.Metadata.Name),
+		},
+		Spec: pvcSpec,
+	}
+
+	created, err := c.config.KubeCli.Core().PersistentVolumeClaims(c.cluster.Metadata.Namespace).Create(pvc)
+	if err != nil {
+		if !k8sutil.IsKubernetesResourceAlreadyExistError(err) {
+			return nil, err
+		}
+		return c.config.KubeCli.Core().PersistentVolumeClaims(c.cluster.Metadata.Namespace).Get(m.Name)
+	}
+	return created, nil
+}
+
+// deleteMemberPVC removes the PVC for the named member, if any. It is a
+// no-op if the PVC is already gone.
+func (c *Cluster) deleteMemberPVC(name string) error {
+	err := c.config.KubeCli.Core().PersistentVolumeClaims(c.cluster.Metadata.Namespace).Delete(name, nil)
+	if err != nil && !k8sutil.IsKubernetesResourceNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+// shouldRetainPVCOnRemove reports whether removePodAndService should leave a
+// member's PVC in place for possible reattachment.
+func (c *Cluster) shouldRetainPVCOnRemove() bool {
+	if c.cluster.Spec.Pod.PersistentVolumeClaimSpec == nil {
+		return false
+	}
+	switch c.cluster.Spec.Pod.PVCDeletePolicy {
+	case spec.PVCDeletePolicyDelete:
+		return false
+	case spec.PVCDeletePolicyRetain, spec.PVCDeletePolicyRetainOnScaleDown, "":
+		// "" means PVCDeletePolicy wasn't set; PVCDeletePolicyRetainOnScaleDown
+		// is documented as the default whenever PersistentVolumeClaimSpec is
+		// set, so treat it the same as an explicit RetainOnScaleDown here.
+		return true
+	default:
+		return false
+	}
+}
+
+// deleteAllMemberPVCs reclaims every PVC this cluster owns. It is called
+// when the whole cluster is deleted, and honors PVCDeletePolicyRetain only:
+// PVCDeletePolicyRetainOnScaleDown exists precisely to still reclaim storage
+// at this point.
+func (c *Cluster) deleteAllMemberPVCs() error {
+	if c.cluster.Spec.Pod.PersistentVolumeClaimSpec == nil {
+		return nil
+	}
+	if c.cluster.Spec.Pod.PVCDeletePolicy == spec.PVCDeletePolicyRetain {
+		return nil
+	}
+
+	pvcs, err := c.config.KubeCli.Core().PersistentVolumeClaims(c.cluster.Metadata.Namespace).List(
+		k8sutil.ClusterListOpt(c.cluster.Metadata.Name))
+	if err != nil {
+		return err
+	}
+	for i := range pvcs.Items {
+		if err := c.deleteMemberPVC(pvcs.Items[i].Name); err != nil {
+			c.logger.Errorf("failed to delete PVC (%s): %v", pvcs.Items[i].Name, err)
+		}
+	}
+	return nil
+}
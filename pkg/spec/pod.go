@@ -0,0 +1,71 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"k8s.io/client-go/1.5/pkg/api/v1"
+)
+
+// PVCDeletePolicy controls what happens to a member's PersistentVolumeClaim
+// once it is no longer attached to a running pod.
+type PVCDeletePolicy string
+
+const (
+	// PVCDeletePolicyDelete removes the PVC as soon as its pod is removed,
+	// whether that's a scale-down, a member replacement, or a cluster delete.
+	PVCDeletePolicyDelete PVCDeletePolicy = "Delete"
+	// PVCDeletePolicyRetain never removes the PVC; operators reclaim the
+	// storage themselves.
+	PVCDeletePolicyRetain PVCDeletePolicy = "Retain"
+	// PVCDeletePolicyRetainOnScaleDown keeps the PVC across scale-downs and
+	// member replacements, so a member can pick its data back up, but still
+	// reclaims storage when the whole cluster is deleted. This is the
+	// default when a PersistentVolumeClaimSpec is set.
+	PVCDeletePolicyRetainOnScaleDown PVCDeletePolicy = "RetainOnScaleDown"
+)
+
+// PodPolicy customizes the pods etcd-operator creates for cluster members.
+type PodPolicy struct {
+	// PersistentVolumeClaimSpec, if set, makes each member's data directory
+	// backed by its own PVC instead of an emptyDir. The claim created for a
+	// member is named after it, so it can be recovered after pod restarts.
+	PersistentVolumeClaimSpec *v1.PersistentVolumeClaimSpec `json:"persistentVolumeClaimSpec,omitempty"`
+
+	// PVCDeletePolicy governs when a member's PVC is removed. Defaults to
+	// PVCDeletePolicyRetainOnScaleDown when PersistentVolumeClaimSpec is set.
+	PVCDeletePolicy PVCDeletePolicy `json:"pvcDeletePolicy,omitempty"`
+
+	// Sidecars are extra containers merged into each member pod alongside
+	// the etcd container, e.g. a metrics exporter or a log shipper. A
+	// sidecar's name must not collide with the etcd container's.
+	Sidecars []v1.Container `json:"sidecars,omitempty"`
+
+	// ExtraEnvs are environment variables merged into the etcd container's
+	// env, e.g. to point an auth proxy sidecar's config at the same pod.
+	// Reserved ETCD_*/ETCDCTL_* names are rejected unless AllowEtcdEnvOverride
+	// is set.
+	ExtraEnvs []v1.EnvVar `json:"extraEnvs,omitempty"`
+
+	// ExtraVolumes and ExtraVolumeMounts are merged into the pod spec and
+	// the etcd container respectively, typically to back a sidecar's own
+	// storage or config.
+	ExtraVolumes      []v1.Volume      `json:"extraVolumes,omitempty"`
+	ExtraVolumeMounts []v1.VolumeMount `json:"extraVolumeMounts,omitempty"`
+
+	// AllowEtcdEnvOverride permits ExtraEnvs to override the operator's own
+	// ETCD_*/ETCDCTL_* environment variables. Off by default: overriding
+	// them by accident is a common way to silently break a member.
+	AllowEtcdEnvOverride bool `json:"allowEtcdEnvOverride,omitempty"`
+}
@@ -0,0 +1,219 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"time"
+)
+
+type ClusterPhase string
+
+const (
+	ClusterPhaseNone     ClusterPhase = ""
+	ClusterPhaseCreating ClusterPhase = "Creating"
+	ClusterPhaseRunning  ClusterPhase = "Running"
+	ClusterPhaseFailed   ClusterPhase = "Failed"
+)
+
+// ClusterConditionType is the type of a ClusterCondition.
+type ClusterConditionType string
+
+const (
+	// ClusterConditionAvailable means the cluster's client endpoint is serving requests.
+	ClusterConditionAvailable ClusterConditionType = "Available"
+	// ClusterConditionRecovering means the cluster lost a quorum and is restoring from backup.
+	ClusterConditionRecovering ClusterConditionType = "Recovering"
+	// ClusterConditionScaling means the cluster is adding or removing members.
+	ClusterConditionScaling ClusterConditionType = "Scaling"
+	// ClusterConditionUpgrading means the cluster is rolling members to a new version.
+	ClusterConditionUpgrading ClusterConditionType = "Upgrading"
+	// ClusterConditionBackupHealthy means the configured backup provider last succeeded.
+	ClusterConditionBackupHealthy ClusterConditionType = "BackupHealthy"
+	// ClusterConditionAlarm means at least one member has an active etcd
+	// alarm raised (e.g. NOSPACE).
+	ClusterConditionAlarm ClusterConditionType = "Alarm"
+)
+
+// ConditionStatus mirrors the k8s.io/api/core/v1.ConditionStatus tri-state:
+// a condition is never simply true/false, it can also be Unknown.
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// ClusterCondition describes one fine-grained aspect of cluster state, in the
+// same spirit as NodeCondition/PodCondition upstream.
+type ClusterCondition struct {
+	Type               ClusterConditionType `json:"type"`
+	Status             ConditionStatus      `json:"status"`
+	Reason             string               `json:"reason,omitempty"`
+	Message            string               `json:"message,omitempty"`
+	LastTransitionTime time.Time            `json:"lastTransitionTime,omitempty"`
+}
+
+type ClusterStatus struct {
+	// Phase is the current high level lifecycle phase of the cluster.
+	Phase ClusterPhase `json:"phase"`
+	// Reason explains why the cluster is in a Failed phase. It is cleared
+	// whenever the cluster recovers.
+	Reason string `json:"reason,omitempty"`
+
+	// ControlPaused indicates the reconcile loop is currently not
+	// processing this cluster because Spec.Paused is set.
+	ControlPaused bool `json:"controlPaused,omitempty"`
+
+	// Size is the current number of members in the cluster.
+	Size int `json:"size"`
+
+	// Conditions is a list of fine-grained cluster conditions, keyed by
+	// type. kubectl get etcdcluster -o wide surfaces these so operators
+	// can see why a cluster isn't Available without digging through logs.
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+}
+
+// Copy returns a deep copy of the status, safe to mutate independently of
+// the original (e.g. to snapshot Status before an in-place update).
+func (cs ClusterStatus) Copy() ClusterStatus {
+	newCS := cs
+	newCS.Conditions = make([]ClusterCondition, len(cs.Conditions))
+	copy(newCS.Conditions, cs.Conditions)
+	return newCS
+}
+
+func (cs *ClusterStatus) SetPhase(p ClusterPhase) {
+	cs.Phase = p
+}
+
+func (cs *ClusterStatus) SetReason(r string) {
+	cs.Reason = r
+}
+
+func (cs *ClusterStatus) PauseControl() {
+	cs.ControlPaused = true
+}
+
+func (cs *ClusterStatus) Control() {
+	cs.ControlPaused = false
+}
+
+// SetCondition upserts cond into Conditions by Type: if a condition of the
+// same type already exists, its Status/Reason/Message are updated in place
+// and LastTransitionTime only advances when Status actually flips, rather
+// than on every reconcile tick. Otherwise cond is appended with the current
+// time as its initial transition. As in the tke cluster controller, once a
+// condition settles to True, the top-level Reason is cleared: it exists to
+// explain failures, not steady-state success. ClusterStatus has no
+// top-level Message field, so there is no message to clear.
+func (cs *ClusterStatus) SetCondition(cond ClusterCondition) {
+	if cond.LastTransitionTime.IsZero() {
+		cond.LastTransitionTime = time.Now()
+	}
+
+	for i := range cs.Conditions {
+		c := &cs.Conditions[i]
+		if c.Type != cond.Type {
+			continue
+		}
+		if c.Status != cond.Status {
+			c.LastTransitionTime = cond.LastTransitionTime
+		}
+		c.Status = cond.Status
+		c.Reason = cond.Reason
+		c.Message = cond.Message
+		if cond.Status == ConditionTrue {
+			cs.Reason = ""
+		}
+		return
+	}
+
+	if cond.Status == ConditionTrue {
+		cs.Reason = ""
+	}
+	cs.Conditions = append(cs.Conditions, cond)
+}
+
+// AppendScalingUpCondition records that the cluster is growing from `from`
+// to `to` members.
+func (cs *ClusterStatus) AppendScalingUpCondition(from, to int) {
+	cs.SetCondition(ClusterCondition{
+		Type:    ClusterConditionScaling,
+		Status:  ConditionTrue,
+		Reason:  "Scaling",
+		Message: fmt.Sprintf("scaling up from %d to %d members", from, to),
+	})
+}
+
+// AppendRemoveMemberCondition records that member `name` is being removed,
+// e.g. during scale-down or unhealthy-member replacement.
+func (cs *ClusterStatus) AppendRemoveMemberCondition(name string, size int) {
+	cs.SetCondition(ClusterCondition{
+		Type:    ClusterConditionScaling,
+		Status:  ConditionTrue,
+		Reason:  "MemberRemoved",
+		Message: fmt.Sprintf("removed member %s, size now %d", name, size),
+	})
+}
+
+// AppendUpgradingCondition records that a version change from `from` to `to`
+// was requested. Note this only marks the request as seen: nothing in this
+// package sets the Upgrading condition back to False on completion.
+func (cs *ClusterStatus) AppendUpgradingCondition(from, to string) {
+	cs.SetCondition(ClusterCondition{
+		Type:    ClusterConditionUpgrading,
+		Status:  ConditionTrue,
+		Reason:  "VersionChangeRequested",
+		Message: fmt.Sprintf("version change requested from %s to %s", from, to),
+	})
+}
+
+// AppendRecoveringCondition records that the cluster lost quorum and is
+// restoring from a backup.
+func (cs *ClusterStatus) AppendRecoveringCondition() {
+	cs.SetCondition(ClusterCondition{
+		Type:    ClusterConditionRecovering,
+		Status:  ConditionTrue,
+		Reason:  "DisasterRecovery",
+		Message: "all members are dead, restoring from backup",
+	})
+}
+
+// SetAvailable marks the cluster as serving client requests. Like any
+// condition set to True, this clears the top-level Reason; the
+// Scaling/Upgrading/Recovering conditions are left as they are.
+func (cs *ClusterStatus) SetAvailable() {
+	cs.SetCondition(ClusterCondition{
+		Type:   ClusterConditionAvailable,
+		Status: ConditionTrue,
+		Reason: "ClusterReady",
+	})
+}
+
+// SetBackupHealthy records the outcome of the most recent backup attempt.
+func (cs *ClusterStatus) SetBackupHealthy(ok bool, reason, message string) {
+	status := ConditionFalse
+	if ok {
+		status = ConditionTrue
+	}
+	cs.SetCondition(ClusterCondition{
+		Type:    ClusterConditionBackupHealthy,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
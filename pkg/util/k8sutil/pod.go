@@ -0,0 +1,136 @@
+// Copyright 2016 The etcd-operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8sutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/coreos/etcd-operator/pkg/spec"
+	"github.com/coreos/etcd-operator/pkg/util/etcdutil"
+
+	"k8s.io/client-go/1.5/pkg/api/meta/metatypes"
+	"k8s.io/client-go/1.5/pkg/api/v1"
+)
+
+const (
+	etcdContainerName = "etcd"
+	etcdVolumeName    = "etcd-data"
+	etcdDataDir       = "/var/etcd/data"
+	defaultEtcdImage  = "quay.io/coreos/etcd"
+)
+
+// MakeEtcdPod builds the pod spec for one etcd member: the etcd container
+// itself, plus whatever the cluster's PodPolicy asks to merge in. It returns
+// an error if PodPolicy is malformed (e.g. a sidecar named "etcd") rather
+// than silently shipping a pod with the policy only partially applied.
+func MakeEtcdPod(m *etcdutil.Member, initialCluster []string, clusterName, state, token string, cs spec.ClusterSpec, owner metatypes.OwnerReference) (*v1.Pod, error) {
+	pod := &v1.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:            m.Name,
+			Labels:          LabelsForCluster(clusterName),
+			OwnerReferences: []metatypes.OwnerReference{owner},
+		},
+		Spec: v1.PodSpec{
+			Containers:    []v1.Container{etcdContainer(m, initialCluster, clusterName, state, token, cs)},
+			RestartPolicy: v1.RestartPolicyNever,
+			Volumes: []v1.Volume{
+				{Name: etcdVolumeName, VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}},
+			},
+		},
+	}
+
+	if err := mergePodPolicy(pod, cs.Pod); err != nil {
+		return nil, fmt.Errorf("invalid pod policy: %v", err)
+	}
+
+	return pod, nil
+}
+
+func etcdContainer(m *etcdutil.Member, initialCluster []string, clusterName, state, token string, cs spec.ClusterSpec) v1.Container {
+	return v1.Container{
+		Name:  etcdContainerName,
+		Image: fmt.Sprintf("%s:%s", defaultEtcdImage, cs.Version),
+		Env: []v1.EnvVar{
+			{Name: "ETCD_NAME", Value: m.Name},
+			{Name: "ETCD_DATA_DIR", Value: etcdDataDir},
+			{Name: "ETCD_INITIAL_CLUSTER", Value: strings.Join(initialCluster, ",")},
+			{Name: "ETCD_INITIAL_CLUSTER_STATE", Value: state},
+			{Name: "ETCD_INITIAL_CLUSTER_TOKEN", Value: token},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{Name: etcdVolumeName, MountPath: etcdDataDir},
+		},
+	}
+}
+
+// mergePodPolicy folds PodPolicy's sidecars, extra env vars, and extra
+// volumes into pod, validating the two invariants MakeEtcdPod promises:
+// no sidecar shadows the etcd container's name, and no extra env var silently
+// overrides an ETCD_*/ETCDCTL_* env var the operator depends on.
+func mergePodPolicy(pod *v1.Pod, pp spec.PodPolicy) error {
+	for _, sc := range pp.Sidecars {
+		if sc.Name == etcdContainerName {
+			return fmt.Errorf("sidecar container name %q collides with the etcd container", sc.Name)
+		}
+	}
+
+	if !pp.AllowEtcdEnvOverride {
+		for _, env := range pp.ExtraEnvs {
+			if isReservedEtcdEnv(env.Name) {
+				return fmt.Errorf("extra env %q overrides a reserved ETCD_*/ETCDCTL_* variable; set AllowEtcdEnvOverride to allow this", env.Name)
+			}
+		}
+	}
+
+	etcdIdx := 0
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == etcdContainerName {
+			etcdIdx = i
+			break
+		}
+	}
+	pod.Spec.Containers[etcdIdx].Env = append(pod.Spec.Containers[etcdIdx].Env, pp.ExtraEnvs...)
+	pod.Spec.Containers[etcdIdx].VolumeMounts = append(pod.Spec.Containers[etcdIdx].VolumeMounts, pp.ExtraVolumeMounts...)
+
+	pod.Spec.Containers = append(pod.Spec.Containers, pp.Sidecars...)
+	pod.Spec.Volumes = append(pod.Spec.Volumes, pp.ExtraVolumes...)
+
+	return nil
+}
+
+func isReservedEtcdEnv(name string) bool {
+	return strings.HasPrefix(name, "ETCD_") || strings.HasPrefix(name, "ETCDCTL_")
+}
+
+// AddEtcdVolumeToPod mounts a PVC named pvcName as the etcd data volume,
+// replacing the emptyDir MakeEtcdPod defaults to.
+func AddEtcdVolumeToPod(pod *v1.Pod, pvcName string) {
+	vol := v1.Volume{
+		Name: etcdVolumeName,
+		VolumeSource: v1.VolumeSource{
+			PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+				ClaimName: pvcName,
+			},
+		},
+	}
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == etcdVolumeName {
+			pod.Spec.Volumes[i] = vol
+			return
+		}
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, vol)
+}